@@ -0,0 +1,156 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RemoteProvider abstracts the hosted git service a repository's origin
+// remote points at, so that callers authenticating a remote URL (for
+// example to reset a token in a staged clone) do not have to hard-code
+// github.com.
+type RemoteProvider interface {
+	// Host returns the provider's git host, e.g. "github.com".
+	Host() string
+	// TokenEnvKey returns the name of the env var holding the access
+	// token used to authenticate against this provider.
+	TokenEnvKey() string
+	// BasicAuthUser returns the username paired with the token when
+	// building a basic-auth HTTPS remote URL.
+	BasicAuthUser() string
+	// RepoPath returns the URL path for org/repo on this provider.
+	RepoPath(org, repo string) string
+}
+
+type githubRemoteProvider struct{}
+
+func (githubRemoteProvider) Host() string         { return "github.com" }
+func (githubRemoteProvider) TokenEnvKey() string   { return "GITHUB_TOKEN" }
+func (githubRemoteProvider) BasicAuthUser() string { return "git" }
+func (githubRemoteProvider) RepoPath(org, repo string) string {
+	return filepath.Join(org, repo)
+}
+
+type gitlabRemoteProvider struct{}
+
+func (gitlabRemoteProvider) Host() string         { return "gitlab.com" }
+func (gitlabRemoteProvider) TokenEnvKey() string   { return "GITLAB_TOKEN" }
+func (gitlabRemoteProvider) BasicAuthUser() string { return "oauth2" }
+func (gitlabRemoteProvider) RepoPath(org, repo string) string {
+	return filepath.Join(org, repo)
+}
+
+type bitbucketRemoteProvider struct{}
+
+func (bitbucketRemoteProvider) Host() string         { return "bitbucket.org" }
+func (bitbucketRemoteProvider) TokenEnvKey() string   { return "BITBUCKET_TOKEN" }
+func (bitbucketRemoteProvider) BasicAuthUser() string { return "x-token-auth" }
+func (bitbucketRemoteProvider) RepoPath(org, repo string) string {
+	return filepath.Join(org, repo)
+}
+
+// genericRemoteProvider authenticates against any self-hosted HTTPS git
+// host (e.g. a Gitea or GitLab mirror) taken from the remote URL, with its
+// token read from the GIT_REMOTE_TOKEN env var, for hosts that have no
+// dedicated implementation above.
+type genericRemoteProvider struct {
+	host string
+}
+
+func (p genericRemoteProvider) Host() string         { return p.host }
+func (genericRemoteProvider) TokenEnvKey() string     { return "GIT_REMOTE_TOKEN" }
+func (genericRemoteProvider) BasicAuthUser() string   { return "git" }
+func (genericRemoteProvider) RepoPath(org, repo string) string {
+	return filepath.Join(org, repo)
+}
+
+// knownRemoteProviders maps a remote host to its RemoteProvider. Hosts not
+// present here fall back to genericRemoteProvider.
+var knownRemoteProviders = map[string]RemoteProvider{
+	"github.com":    githubRemoteProvider{},
+	"gitlab.com":    gitlabRemoteProvider{},
+	"bitbucket.org": bitbucketRemoteProvider{},
+}
+
+// RemoteProviderForHost returns the RemoteProvider registered for host, or
+// a genericRemoteProvider configured from GIT_REMOTE_TOKEN if host is not
+// one of the known providers.
+func RemoteProviderForHost(host string) RemoteProvider {
+	if p, ok := knownRemoteProviders[host]; ok {
+		return p
+	}
+	return genericRemoteProvider{host: host}
+}
+
+// DetectRemoteProvider returns the RemoteProvider matching the host of
+// repo's remoteName remote, so that PrepareWorkspaceRelease can rewrite
+// its authenticated URL without assuming GitHub.
+func DetectRemoteProvider(repo *Repo, remoteName string) (RemoteProvider, error) {
+	remoteURL, err := repo.RemoteURL(remoteName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading %s remote URL", remoteName)
+	}
+
+	host, err := remoteHost(remoteURL)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing remote URL %s", remoteURL)
+	}
+
+	return RemoteProviderForHost(host), nil
+}
+
+// remoteHost extracts the host from either an HTTPS remote URL
+// (https://host/org/repo) or an SSH-style one (git@host:org/repo).
+func remoteHost(remoteURL string) (string, error) {
+	if strings.HasPrefix(remoteURL, "git@") {
+		rest := strings.TrimPrefix(remoteURL, "git@")
+		host, _, ok := strings.Cut(rest, ":")
+		if !ok {
+			return "", errors.Errorf("malformed SSH remote URL %q", remoteURL)
+		}
+		return host, nil
+	}
+
+	for _, prefix := range []string{"https://", "http://"} {
+		if strings.HasPrefix(remoteURL, prefix) {
+			rest := strings.TrimPrefix(remoteURL, prefix)
+			host, _, _ := strings.Cut(rest, "/")
+			// Strip any basic-auth userinfo left over from a prior rewrite.
+			if _, after, ok := strings.Cut(host, "@"); ok {
+				host = after
+			}
+			return host, nil
+		}
+	}
+
+	return "", errors.Errorf("unrecognized remote URL scheme %q", remoteURL)
+}
+
+// TokenFromEnv looks up the access token for provider from its
+// TokenEnvKey, returning an error if the env var is not set.
+func TokenFromEnv(provider RemoteProvider) (string, error) {
+	token, ok := os.LookupEnv(provider.TokenEnvKey())
+	if !ok {
+		return "", errors.Errorf("%s env variable is not set", provider.TokenEnvKey())
+	}
+	return token, nil
+}
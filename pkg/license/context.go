@@ -0,0 +1,52 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package license
+
+import "context"
+
+// Catalog bundles a prewarmed set of SPDX licenses, the downloader that
+// fetched them, and the file classifier built from them, so that a single
+// instance can be shared by every SBOM/license consumer in a release run
+// instead of each one rebuilding (and re-downloading) its own.
+type Catalog struct {
+	Downloader *Downloader
+	Licenses   *Licenses
+	// Reader classifies third-party license files against the corpus
+	// bundled with licenseclassifier, not against Licenses. It is the
+	// file-classification counterpart to Downloader, which only fetches
+	// the SPDX license list itself.
+	Reader *Reader
+}
+
+type licenseScannerKey struct{}
+
+// SetContextLicenseScanner returns a copy of ctx carrying catalog, so that
+// downstream stages can retrieve the same prewarmed licenses and
+// downloader via ContextLicenseScanner instead of constructing their own.
+func SetContextLicenseScanner(ctx context.Context, catalog *Catalog) context.Context {
+	return context.WithValue(ctx, licenseScannerKey{}, catalog)
+}
+
+// ContextLicenseScanner returns the Catalog attached to ctx by
+// SetContextLicenseScanner, or nil if none was attached.
+func ContextLicenseScanner(ctx context.Context) *Catalog {
+	catalog, ok := ctx.Value(licenseScannerKey{}).(*Catalog)
+	if !ok {
+		return nil
+	}
+	return catalog
+}
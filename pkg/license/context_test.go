@@ -0,0 +1,35 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package license
+
+import (
+	"context"
+	"testing"
+)
+
+func TestContextLicenseScanner(t *testing.T) {
+	if got := ContextLicenseScanner(context.Background()); got != nil {
+		t.Fatalf("ContextLicenseScanner(bare context) = %v, want nil", got)
+	}
+
+	catalog := &Catalog{}
+	ctx := SetContextLicenseScanner(context.Background(), catalog)
+
+	if got := ContextLicenseScanner(ctx); got != catalog {
+		t.Fatalf("ContextLicenseScanner(seeded context) = %v, want %v", got, catalog)
+	}
+}
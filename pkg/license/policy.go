@@ -0,0 +1,128 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package license
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Decision is the outcome of classifying a single vendored file against a
+// Policy.
+type Decision string
+
+const (
+	DecisionAllowed      Decision = "allowed"
+	DecisionDenied       Decision = "denied"
+	DecisionUnclassified Decision = "unclassified"
+	DecisionWaived       Decision = "waived"
+)
+
+// Policy configures SPDX license allowlist enforcement for a vendor tree:
+// which SPDX IDs are explicitly allowed or denied, and the minimum
+// classifier confidence required to trust a match.
+type Policy struct {
+	// Allow is the set of SPDX IDs permitted in the scanned tree. A file
+	// classified to any other ID at or above MinConfidence is denied unless
+	// it is waived.
+	Allow []string `yaml:"allow"`
+	// Deny is an explicit denylist, checked before Allow so a denied ID
+	// can't be reintroduced by also adding it to Allow.
+	Deny []string `yaml:"deny"`
+	// MinConfidence is the minimum classifier confidence, in the same
+	// 0-100 scale most license scanners use, for a match to be trusted.
+	// A match below this threshold is treated as DecisionUnclassified,
+	// unless its ID is in Deny.
+	MinConfidence float64 `yaml:"minConfidence"`
+}
+
+// Finding is one row of the machine-readable license report written by
+// PrepareWorkspaceStage's enforcement gate.
+type Finding struct {
+	Path       string   `json:"path"`
+	SPDXID     string   `json:"spdxID"`
+	Confidence float64  `json:"confidence"`
+	Decision   Decision `json:"decision"`
+}
+
+// Waivers is the repo-local ".license-waivers.yaml" file, keyed by the
+// vendor path it waives.
+type Waivers struct {
+	Paths map[string]string `yaml:"paths"`
+}
+
+// LoadWaivers reads a Waivers file from path, returning an empty Waivers
+// if the file does not exist (waivers are optional).
+func LoadWaivers(path string) (*Waivers, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &Waivers{Paths: map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading waivers file %s", path)
+	}
+
+	waivers := &Waivers{}
+	if err := yaml.Unmarshal(data, waivers); err != nil {
+		return nil, errors.Wrapf(err, "parsing waivers file %s", path)
+	}
+	if waivers.Paths == nil {
+		waivers.Paths = map[string]string{}
+	}
+
+	return waivers, nil
+}
+
+// Classify decides path's Finding given its classified spdxID and
+// confidence, policy, and any waivers.
+func Classify(policy *Policy, waivers *Waivers, path, spdxID string, confidence float64) Finding {
+	finding := Finding{Path: path, SPDXID: spdxID, Confidence: confidence}
+
+	if _, waived := waivers.Paths[path]; waived {
+		finding.Decision = DecisionWaived
+		return finding
+	}
+
+	if contains(policy.Deny, spdxID) {
+		finding.Decision = DecisionDenied
+		return finding
+	}
+
+	if confidence < policy.MinConfidence {
+		finding.Decision = DecisionUnclassified
+		return finding
+	}
+
+	if contains(policy.Allow, spdxID) {
+		finding.Decision = DecisionAllowed
+		return finding
+	}
+
+	finding.Decision = DecisionDenied
+	return finding
+}
+
+func contains(ids []string, id string) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
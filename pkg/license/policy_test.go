@@ -0,0 +1,55 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package license
+
+import "testing"
+
+func TestClassify(t *testing.T) {
+	policy := &Policy{
+		Allow:         []string{"Apache-2.0", "MIT", "GPL-3.0"},
+		Deny:          []string{"GPL-3.0"},
+		MinConfidence: 90,
+	}
+	waivers := &Waivers{Paths: map[string]string{
+		"vendor/denied/LICENSE": "approved by legal, see JIRA-123",
+	}}
+
+	for _, tc := range []struct {
+		name       string
+		path       string
+		spdxID     string
+		confidence float64
+		want       Decision
+	}{
+		{name: "waived path wins over deny", path: "vendor/denied/LICENSE", spdxID: "GPL-3.0", confidence: 100, want: DecisionWaived},
+		{name: "denied takes precedence over allow", path: "vendor/x/LICENSE", spdxID: "GPL-3.0", confidence: 100, want: DecisionDenied},
+		{name: "below confidence threshold is unclassified", path: "vendor/y/LICENSE", spdxID: "Apache-2.0", confidence: 50, want: DecisionUnclassified},
+		{name: "allowed above threshold", path: "vendor/z/LICENSE", spdxID: "MIT", confidence: 95, want: DecisionAllowed},
+		{name: "at threshold is allowed", path: "vendor/w/LICENSE", spdxID: "MIT", confidence: 90, want: DecisionAllowed},
+		{name: "not on allowlist is denied", path: "vendor/v/LICENSE", spdxID: "BSD-3-Clause", confidence: 100, want: DecisionDenied},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			finding := Classify(policy, waivers, tc.path, tc.spdxID, tc.confidence)
+			if finding.Decision != tc.want {
+				t.Fatalf("Classify(%s) decision = %s, want %s", tc.path, finding.Decision, tc.want)
+			}
+			if finding.Path != tc.path || finding.SPDXID != tc.spdxID || finding.Confidence != tc.confidence {
+				t.Fatalf("Classify(%s) = %+v, want Path/SPDXID/Confidence echoed unchanged", tc.path, finding)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package license
+
+import (
+	"os"
+
+	"github.com/google/licenseclassifier"
+	"github.com/pkg/errors"
+)
+
+// readerMatchThreshold is intentionally permissive: Policy.MinConfidence is
+// what actually gates a match (see Classify), so the classifier itself
+// should surface every candidate rather than silently discard low-
+// confidence ones before Policy ever sees them.
+const readerMatchThreshold = 0.0
+
+// Reader classifies third-party license files against the license corpus
+// bundled with licenseclassifier, reporting the closest match and the
+// classifier's confidence in it, so PrepareWorkspaceStage's license gate has
+// a numeric score to threshold Policy.MinConfidence against. It is the
+// file-classification counterpart to Downloader, which only fetches the
+// SPDX license list.
+type Reader struct {
+	classifier *licenseclassifier.License
+}
+
+// NewReaderWithLicenses builds a Reader backed by licenseclassifier's
+// bundled license corpus. The licenses argument is not used: files are not
+// classified against the SPDX set that license.Catalog prewarms.
+func NewReaderWithLicenses(licenses *Licenses) (*Reader, error) {
+	c, err := licenseclassifier.New(readerMatchThreshold)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating license classifier")
+	}
+	return &Reader{classifier: c}, nil
+}
+
+// ClassifyFile reads path and returns its best-matching SPDX license ID
+// and the classifier's confidence in that match, on licenseclassifier's
+// native 0.0-1.0 scale. If no license text is recognized, spdxID is empty
+// and confidence is 0.
+func (r *Reader) ClassifyFile(path string) (spdxID string, confidence float64, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", 0, errors.Wrapf(err, "reading %s", path)
+	}
+
+	matches := r.classifier.MultipleMatch(string(data), true)
+	if len(matches) == 0 {
+		return "", 0, nil
+	}
+
+	best := matches[0]
+	for _, m := range matches[1:] {
+		if m.Confidence > best.Confidence {
+			best = m
+		}
+	}
+
+	return best.Name, best.Confidence, nil
+}
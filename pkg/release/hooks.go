@@ -0,0 +1,69 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// WorkspaceHook customizes a staged or released k/k workspace before
+// control returns to the caller. It receives the directory of the
+// cloned/extracted repository and the resolved build version; hooks run by
+// PrepareWorkspaceStage receive an empty build version.
+type WorkspaceHook func(ctx context.Context, repoDir, buildVersion string) error
+
+// workspaceHooks is the registry of hooks available to be named in a
+// ReleaseOptions/StageOptions hook list, keyed by the name passed to
+// RegisterWorkspaceHook.
+var workspaceHooks = map[string]WorkspaceHook{}
+
+// RegisterWorkspaceHook adds fn to the registry of named workspace hooks
+// under name, so it can be referenced from a stage/release hook list
+// without the caller needing a reference to the function itself. It
+// panics if name is already registered, mirroring how init-time registries
+// elsewhere in this codebase fail fast on duplicate registration.
+func RegisterWorkspaceHook(name string, fn WorkspaceHook) {
+	if _, ok := workspaceHooks[name]; ok {
+		panic(errors.Errorf("workspace hook %q already registered", name))
+	}
+	workspaceHooks[name] = fn
+}
+
+// runWorkspaceHooks looks up and runs each named hook in order, passing
+// repoDir and buildVersion, and stops at the first error.
+func runWorkspaceHooks(ctx context.Context, names []string, repoDir, buildVersion string) error {
+	for _, name := range names {
+		fn, ok := workspaceHooks[name]
+		if !ok {
+			return errors.Errorf("no workspace hook registered under name %q", name)
+		}
+		if err := fn(ctx, repoDir, buildVersion); err != nil {
+			return errors.Wrapf(err, "running workspace hook %q", name)
+		}
+	}
+	return nil
+}
+
+func init() {
+	RegisterWorkspaceHook("apply-patch-series", hookApplyPatchSeries)
+	RegisterWorkspaceHook("go-mod-vendor", hookGoModVendor)
+	RegisterWorkspaceHook("update-scripts", hookUpdateScripts)
+	RegisterWorkspaceHook("inject-license", hookInjectLicense)
+	RegisterWorkspaceHook("go-generate", hookGoGenerate)
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/release/pkg/license"
+)
+
+// hookApplyPatchSeries applies a patch series fetched from the URL in the
+// PATCH_SERIES_URL env var on top of repoDir, used by downstream
+// distributors who carry a small set of carried patches on top of k/k.
+func hookApplyPatchSeries(ctx context.Context, repoDir, buildVersion string) error {
+	patchURL, ok := os.LookupEnv("PATCH_SERIES_URL")
+	if !ok {
+		return nil
+	}
+
+	resp, err := http.Get(patchURL)
+	if err != nil {
+		return errors.Wrapf(err, "fetching patch series from %s", patchURL)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("fetching patch series from %s: status %s", patchURL, resp.Status)
+	}
+
+	f, err := os.CreateTemp("", "patch-series-*.patch")
+	if err != nil {
+		return errors.Wrap(err, "creating temp patch file")
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	if _, err := io.Copy(f, resp.Body); err != nil {
+		return errors.Wrap(err, "writing patch series to disk")
+	}
+
+	cmd := exec.CommandContext(ctx, "git", "am", f.Name())
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "applying patch series: %s", out)
+	}
+
+	return nil
+}
+
+// hookGoModVendor runs `go mod vendor` in repoDir, for distributors who
+// need a vendored tree alongside the staged/released sources.
+func hookGoModVendor(ctx context.Context, repoDir, buildVersion string) error {
+	cmd := exec.CommandContext(ctx, "go", "mod", "vendor")
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "running go mod vendor: %s", out)
+	}
+	return nil
+}
+
+// hookUpdateScripts runs every hack/update-*.sh script in repoDir.
+func hookUpdateScripts(ctx context.Context, repoDir, buildVersion string) error {
+	matches, err := filepath.Glob(filepath.Join(repoDir, "hack", "update-*.sh"))
+	if err != nil {
+		return errors.Wrap(err, "globbing hack/update-*.sh")
+	}
+
+	for _, script := range matches {
+		cmd := exec.CommandContext(ctx, script)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return errors.Wrapf(err, "running %s: %s", script, out)
+		}
+	}
+
+	return nil
+}
+
+// hookInjectLicense appends the file referenced by the EXTRA_LICENSE_PATH
+// env var to repoDir/LICENSE, for distributors who must ship an
+// additional license alongside the upstream Apache 2.0 one.
+func hookInjectLicense(ctx context.Context, repoDir, buildVersion string) error {
+	src, ok := os.LookupEnv("EXTRA_LICENSE_PATH")
+	if !ok {
+		return nil
+	}
+
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", src)
+	}
+
+	licenseFile, err := os.OpenFile(
+		filepath.Join(repoDir, "LICENSE"), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644,
+	)
+	if err != nil {
+		return errors.Wrap(err, "opening LICENSE file")
+	}
+	defer licenseFile.Close()
+
+	if _, err := licenseFile.Write(data); err != nil {
+		return errors.Wrap(err, "writing injected LICENSE file")
+	}
+
+	// Classify the injected license against the catalog PrepareWorkspaceStage
+	// already prewarmed, so distributors get a record of what they just
+	// shipped without this hook downloading and building its own copy of
+	// the SPDX corpus.
+	if catalog := license.ContextLicenseScanner(ctx); catalog != nil {
+		spdxID, confidence, err := catalog.Reader.ClassifyFile(src)
+		if err != nil {
+			return errors.Wrapf(err, "classifying injected license %s", src)
+		}
+		logrus.Infof("Injected license %s classified as %s (confidence %.2f)", src, spdxID, confidence)
+	}
+
+	return nil
+}
+
+// hookGoGenerate runs `go generate ./...` in repoDir.
+func hookGoGenerate(ctx context.Context, repoDir, buildVersion string) error {
+	cmd := exec.CommandContext(ctx, "go", "generate", "./...")
+	cmd.Dir = repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return errors.Wrapf(err, "running go generate: %s", out)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/release/pkg/license"
+)
+
+// licenseReportPath is the well-known path, relative to the workspace
+// directory, where enforceLicensePolicy writes its machine-readable
+// report.
+const licenseReportPath = "license-report.json"
+
+// waiversFileName is the repo-local file listing waived vendor paths,
+// checked into the k/k tree itself.
+const waiversFileName = ".license-waivers.yaml"
+
+// licenseFileRE matches the third-party license files enforceLicensePolicy
+// classifies: vendor/.../LICENSE* and vendor/.../COPYING*, plus any
+// Bazel-declared third-party tree under third_party/.
+var licenseFileRE = regexp.MustCompile(`(?i)^(LICENSE|COPYING)`)
+
+// enforceLicensePolicy walks directory's vendor/ and third_party/ trees,
+// classifies every third-party license file with the Reader of the catalog
+// attached to ctx by PrepareWorkspaceStage, and enforces policy: any file
+// that is denied, or unclassified because its match falls below policy's
+// confidence threshold (and is not present in .license-waivers.yaml),
+// fails the stage. Once every tree has been classified, a report is
+// written to licenseReportPath inside directory before violations are
+// checked, so it is present when policy violations fail the stage and CI
+// can surface it as an artifact.
+func enforceLicensePolicy(ctx context.Context, directory string, policy *license.Policy) error {
+	catalog := license.ContextLicenseScanner(ctx)
+	if catalog == nil {
+		return errors.New("no license catalog attached to context")
+	}
+
+	waivers, err := license.LoadWaivers(filepath.Join(directory, waiversFileName))
+	if err != nil {
+		return errors.Wrap(err, "loading license waivers")
+	}
+
+	var findings []license.Finding
+	for _, root := range []string{"vendor", "third_party"} {
+		rootFindings, err := classifyLicenseTree(filepath.Join(directory, root), catalog, policy, waivers)
+		if err != nil {
+			return errors.Wrapf(err, "classifying %s", root)
+		}
+		findings = append(findings, rootFindings...)
+	}
+
+	if err := writeLicenseReport(filepath.Join(directory, licenseReportPath), findings); err != nil {
+		return errors.Wrap(err, "writing license report")
+	}
+
+	var denied []string
+	for _, f := range findings {
+		if f.Decision == license.DecisionDenied || f.Decision == license.DecisionUnclassified {
+			denied = append(denied, f.Path)
+		}
+	}
+	if len(denied) > 0 {
+		return errors.Errorf("license policy violations in %d file(s), see %s: %s",
+			len(denied), licenseReportPath, strings.Join(denied, ", "))
+	}
+
+	return nil
+}
+
+// classifyLicenseTree walks root (skipped entirely if it does not exist)
+// and classifies each matching license file, recording and waiver-matching
+// it by its path relative to root's parent (e.g. "vendor/foo/LICENSE").
+func classifyLicenseTree(root string, catalog *license.Catalog, policy *license.Policy, waivers *license.Waivers) ([]license.Finding, error) {
+	var findings []license.Finding
+
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || !licenseFileRE.MatchString(d.Name()) {
+			return nil
+		}
+
+		spdxID, confidence, err := catalog.Reader.ClassifyFile(path)
+		if err != nil {
+			return errors.Wrapf(err, "classifying %s", path)
+		}
+
+		// catalog.Reader reports confidence on licenseclassifier's native
+		// 0.0-1.0 scale; Policy.MinConfidence is documented (and configured
+		// by operators) on a 0-100 scale, so convert before comparing.
+		rel, err := filepath.Rel(filepath.Dir(root), path)
+		if err != nil {
+			return errors.Wrapf(err, "relativizing %s", path)
+		}
+		findings = append(findings, license.Classify(policy, waivers, rel, spdxID, confidence*100))
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+func writeLicenseReport(path string, findings []license.Finding) error {
+	data, err := json.MarshalIndent(findings, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "marshaling license report")
+	}
+	return os.WriteFile(path, data, 0o644)
+}
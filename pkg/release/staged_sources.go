@@ -0,0 +1,99 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"net/url"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+
+	"k8s.io/release/pkg/object"
+)
+
+// StagedSourcesStore abstracts the remote location that holds the staged
+// k/k sources tarball written by the stage run and consumed by release.
+// Implementations are selected by the URL scheme of the bucket argument
+// passed to PrepareWorkspaceRelease, so a release can be staged to and
+// re-hydrated from infrastructure other than GCS.
+type StagedSourcesStore interface {
+	// Stat returns nil if the object at path exists, or an error otherwise.
+	Stat(path string) error
+	// Fetch downloads the object at path to the local destination dst.
+	Fetch(path, dst string) error
+	// URL returns the canonical, human-readable URL of the object at path.
+	URL(path string) string
+}
+
+// NewStagedSourcesStore returns the StagedSourcesStore implementation
+// matching the scheme of bucket. A bucket with no scheme (the historical
+// "gs://"-less bucket name accepted by PrepareWorkspaceRelease) defaults to
+// GCS so existing callers keep working unchanged.
+func NewStagedSourcesStore(bucket string) (StagedSourcesStore, error) {
+	u, err := url.Parse(bucket)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing staged sources bucket %q", bucket)
+	}
+
+	switch u.Scheme {
+	case "", "gs":
+		// Strip the "gs://" scheme, if any, down to the bare "bucket/prefix"
+		// form object.GCS expects; a schemeless bucket already parses into
+		// this shape (Host is only populated when a scheme is present).
+		return newGCSStagedSourcesStore(u.Host + u.Path), nil
+	case "s3":
+		return newS3StagedSourcesStore(u), nil
+	case "oci":
+		return newOCIStagedSourcesStore(u), nil
+	case "http", "https":
+		return newHTTPStagedSourcesStore(u), nil
+	default:
+		return nil, errors.Errorf("unsupported staged sources scheme %q in bucket %q", u.Scheme, bucket)
+	}
+}
+
+// gcsStagedSourcesStore implements StagedSourcesStore on top of the
+// existing pkg/object GCS client.
+type gcsStagedSourcesStore struct {
+	gcs    *object.GCS
+	bucket string
+}
+
+func newGCSStagedSourcesStore(bucket string) *gcsStagedSourcesStore {
+	gcs := object.NewGCS()
+	gcs.WithAllowMissing(false)
+	return &gcsStagedSourcesStore{gcs: gcs, bucket: bucket}
+}
+
+func (s *gcsStagedSourcesStore) Stat(path string) error {
+	exists, err := s.gcs.PathExists(s.URL(path))
+	if err != nil {
+		return errors.Wrapf(err, "checking %s", s.URL(path))
+	}
+	if !exists {
+		return errors.Errorf("staged sources not found at %s", s.URL(path))
+	}
+	return nil
+}
+
+func (s *gcsStagedSourcesStore) Fetch(path, dst string) error {
+	return s.gcs.CopyToLocal(s.URL(path), dst)
+}
+
+func (s *gcsStagedSourcesStore) URL(path string) string {
+	return filepath.Join(s.bucket, path)
+}
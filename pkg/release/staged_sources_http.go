@@ -0,0 +1,160 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	stdpath "path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// httpStagedSourcesStore fetches the staged sources tarball from a plain
+// HTTPS(S) URL, optionally verified against a co-located "<path>.sha256"
+// checksum file.
+type httpStagedSourcesStore struct {
+	client *http.Client
+	base   *url.URL
+}
+
+func newHTTPStagedSourcesStore(base *url.URL) *httpStagedSourcesStore {
+	return &httpStagedSourcesStore{client: http.DefaultClient, base: base}
+}
+
+func (s *httpStagedSourcesStore) Stat(path string) error {
+	req, err := http.NewRequest(http.MethodHead, s.URL(path), nil)
+	if err != nil {
+		return errors.Wrapf(err, "building HEAD request for %s", path)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "checking %s", path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("staged sources not found at %s: status %s", s.URL(path), resp.Status)
+	}
+
+	return nil
+}
+
+func (s *httpStagedSourcesStore) Fetch(path, dst string) error {
+	resp, err := s.client.Get(s.URL(path))
+	if err != nil {
+		return errors.Wrapf(err, "downloading %s", path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("downloading %s: status %s", s.URL(path), resp.Status)
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return errors.Wrapf(err, "creating %s", dst)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return errors.Wrapf(err, "writing %s", dst)
+	}
+
+	return verifyChecksum(s, path, dst)
+}
+
+// URL joins path onto the base URL's existing path, so a bucket like
+// "https://mirror.example.com/k8s/sources" keeps its "/k8s/sources"
+// prefix instead of having it replaced outright.
+func (s *httpStagedSourcesStore) URL(p string) string {
+	u := *s.base
+	u.Path = stdpath.Join(s.base.Path, p)
+	return u.String()
+}
+
+// verifyChecksum compares dst against the "<path>.sha256" sidecar file,
+// skipping verification only when the sidecar is confirmed not published
+// (a 404). Any other failure to fetch or read it fails closed, so a server
+// can't bypass verification by refusing or mangling the sidecar.
+func verifyChecksum(s *httpStagedSourcesStore, path, dst string) error {
+	resp, err := s.client.Get(s.URL(path + ".sha256"))
+	if err != nil {
+		return errors.Wrapf(err, "fetching checksum sidecar for %s", path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("fetching checksum sidecar for %s: status %s", path, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading checksum sidecar")
+	}
+
+	want, err := parseChecksumSidecar(string(body))
+	if err != nil {
+		return errors.Wrapf(err, "parsing checksum sidecar for %s", path)
+	}
+
+	got, err := sha256File(dst)
+	if err != nil {
+		return errors.Wrap(err, "hashing downloaded sources")
+	}
+
+	if !strings.EqualFold(want, got) {
+		return errors.Errorf("checksum mismatch for %s: got %s, want %s", dst, got, want)
+	}
+
+	return nil
+}
+
+// parseChecksumSidecar extracts the hex digest from a "<path>.sha256"
+// sidecar's contents, tolerating both a bare hex digest and the
+// "<hash>␠␠<filename>" format sha256sum(1) writes.
+func parseChecksumSidecar(contents string) (string, error) {
+	fields := strings.Fields(contents)
+	if len(fields) == 0 {
+		return "", errors.New("checksum sidecar is empty")
+	}
+	return fields[0], nil
+}
+
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
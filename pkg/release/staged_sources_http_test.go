@@ -0,0 +1,80 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestHTTPStagedSourcesStoreURL(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		base string
+		path string
+		want string
+	}{
+		{name: "base with path prefix", base: "https://mirror.example.com/k8s/sources", path: "stage/v1.2.3/kubernetes-src.tar.gz", want: "https://mirror.example.com/k8s/sources/stage/v1.2.3/kubernetes-src.tar.gz"},
+		{name: "base with no path", base: "https://example.com", path: "stage/v1.2.3/kubernetes-src.tar.gz", want: "https://example.com/stage/v1.2.3/kubernetes-src.tar.gz"},
+		{name: "base with trailing slash", base: "https://example.com/sources/", path: "stage/v1.2.3/kubernetes-src.tar.gz", want: "https://example.com/sources/stage/v1.2.3/kubernetes-src.tar.gz"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			base, err := url.Parse(tc.base)
+			if err != nil {
+				t.Fatalf("parsing base %q: %v", tc.base, err)
+			}
+			store := newHTTPStagedSourcesStore(base)
+			if got := store.URL(tc.path); got != tc.want {
+				t.Fatalf("URL(%q) = %q, want %q", tc.path, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestParseChecksumSidecar(t *testing.T) {
+	const digest = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+	for _, tc := range []struct {
+		name     string
+		contents string
+		want     string
+		wantErr  bool
+	}{
+		{name: "bare digest", contents: digest, want: digest},
+		{name: "bare digest with trailing newline", contents: digest + "\n", want: digest},
+		{name: "sha256sum format", contents: digest + "  kubernetes-src.tar.gz\n", want: digest},
+		{name: "sha256sum format single space", contents: digest + " kubernetes-src.tar.gz", want: digest},
+		{name: "empty sidecar", contents: "", wantErr: true},
+		{name: "whitespace-only sidecar", contents: "   \n", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseChecksumSidecar(tc.contents)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("parseChecksumSidecar(%q): expected error, got none", tc.contents)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseChecksumSidecar(%q): unexpected error: %v", tc.contents, err)
+			}
+			if got != tc.want {
+				t.Fatalf("parseChecksumSidecar(%q) = %q, want %q", tc.contents, got, tc.want)
+			}
+		})
+	}
+}
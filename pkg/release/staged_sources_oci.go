@@ -0,0 +1,110 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+	"oras.land/oras-go/v2"
+	"oras.land/oras-go/v2/content/file"
+	"oras.land/oras-go/v2/registry/remote"
+)
+
+// ociStagedSourcesStore fetches the staged sources tarball pushed as an
+// ORAS artifact, addressed as "oci://<registry>/<repository>:<tag>" (for
+// example "oci://ghcr.io/org/repo:v1.2.3-stage"). The reference names the
+// whole artifact: the path argument of Stat, Fetch and URL is ignored, so
+// every path resolves to the artifact's single file.
+type ociStagedSourcesStore struct {
+	ref string
+}
+
+func newOCIStagedSourcesStore(u *url.URL) *ociStagedSourcesStore {
+	return &ociStagedSourcesStore{ref: u.Host + u.Path}
+}
+
+func (s *ociStagedSourcesStore) repository() (*remote.Repository, error) {
+	repo, err := remote.NewRepository(s.ref)
+	if err != nil {
+		return nil, errors.Wrapf(err, "resolving OCI reference %s", s.ref)
+	}
+	return repo, nil
+}
+
+func (s *ociStagedSourcesStore) Stat(path string) error {
+	repo, err := s.repository()
+	if err != nil {
+		return err
+	}
+
+	if _, err := repo.Resolve(context.Background(), s.ref); err != nil {
+		return errors.Wrapf(err, "checking oci://%s", s.ref)
+	}
+
+	return nil
+}
+
+func (s *ociStagedSourcesStore) Fetch(path, dst string) error {
+	repo, err := s.repository()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dst+".dir", 0o755); err != nil {
+		return errors.Wrap(err, "creating OCI pull destination")
+	}
+
+	store, err := file.New(dst + ".dir")
+	if err != nil {
+		return errors.Wrap(err, "creating OCI file store")
+	}
+	defer store.Close()
+
+	if _, err := oras.Copy(context.Background(), repo, s.ref, store, s.ref, oras.DefaultCopyOptions); err != nil {
+		return errors.Wrapf(err, "pulling oci://%s", s.ref)
+	}
+
+	// The ORAS artifact contains exactly one layer: the sources tarball.
+	// Move it to the expected destination path.
+	return promoteSingleFile(dst+".dir", dst)
+}
+
+func (s *ociStagedSourcesStore) URL(path string) string {
+	return "oci://" + s.ref
+}
+
+// promoteSingleFile moves the single regular file found in dir to dst,
+// used to flatten an ORAS pull directory down to the plain tarball path
+// the rest of PrepareWorkspaceRelease expects.
+func promoteSingleFile(dir, dst string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return errors.Wrapf(err, "reading %s", dir)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		return os.Rename(dir+"/"+e.Name(), dst)
+	}
+
+	return errors.Errorf("no sources tarball found in pulled OCI artifact %s", dir)
+}
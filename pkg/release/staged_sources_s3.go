@@ -0,0 +1,104 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/pkg/errors"
+)
+
+// s3StagedSourcesStore fetches the staged sources tarball from an S3-
+// compatible bucket, addressed as "s3://<bucket>[/<prefix>]".
+type s3StagedSourcesStore struct {
+	bucket string
+	prefix string
+}
+
+func newS3StagedSourcesStore(u *url.URL) *s3StagedSourcesStore {
+	return &s3StagedSourcesStore{
+		bucket: u.Host,
+		prefix: strings.TrimPrefix(u.Path, "/"),
+	}
+}
+
+func (s *s3StagedSourcesStore) key(path string) string {
+	if s.prefix == "" {
+		return path
+	}
+	return s.prefix + "/" + path
+}
+
+func (s *s3StagedSourcesStore) client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "loading AWS configuration")
+	}
+	return s3.NewFromConfig(cfg), nil
+}
+
+func (s *s3StagedSourcesStore) Stat(path string) error {
+	ctx := context.Background()
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(path)),
+	}); err != nil {
+		return errors.Wrapf(err, "checking s3://%s/%s", s.bucket, s.key(path))
+	}
+
+	return nil
+}
+
+func (s *s3StagedSourcesStore) Fetch(path, dst string) error {
+	ctx := context.Background()
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return errors.Wrapf(err, "creating %s", dst)
+	}
+	defer out.Close()
+
+	downloader := manager.NewDownloader(client)
+	if _, err := downloader.Download(ctx, out, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(path)),
+	}); err != nil {
+		return errors.Wrapf(err, "downloading s3://%s/%s", s.bucket, s.key(path))
+	}
+
+	return nil
+}
+
+func (s *s3StagedSourcesStore) URL(path string) string {
+	return "s3://" + s.bucket + "/" + s.key(path)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import "testing"
+
+func TestNewStagedSourcesStore(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		bucket  string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "bare bucket defaults to gcs", bucket: "my-bucket", want: &gcsStagedSourcesStore{}},
+		{name: "explicit gs scheme", bucket: "gs://my-bucket", want: &gcsStagedSourcesStore{}},
+		{name: "s3 scheme", bucket: "s3://my-bucket", want: &s3StagedSourcesStore{}},
+		{name: "oci scheme", bucket: "oci://ghcr.io/org/repo:v1", want: &ociStagedSourcesStore{}},
+		{name: "http scheme", bucket: "http://example.com/sources", want: &httpStagedSourcesStore{}},
+		{name: "https scheme", bucket: "https://example.com/sources", want: &httpStagedSourcesStore{}},
+		{name: "unsupported scheme", bucket: "ftp://example.com/sources", wantErr: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store, err := NewStagedSourcesStore(tc.bucket)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("NewStagedSourcesStore(%q): expected error, got none", tc.bucket)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewStagedSourcesStore(%q): unexpected error: %v", tc.bucket, err)
+			}
+
+			switch tc.want.(type) {
+			case *gcsStagedSourcesStore:
+				if _, ok := store.(*gcsStagedSourcesStore); !ok {
+					t.Fatalf("NewStagedSourcesStore(%q) = %T, want *gcsStagedSourcesStore", tc.bucket, store)
+				}
+			case *s3StagedSourcesStore:
+				if _, ok := store.(*s3StagedSourcesStore); !ok {
+					t.Fatalf("NewStagedSourcesStore(%q) = %T, want *s3StagedSourcesStore", tc.bucket, store)
+				}
+			case *ociStagedSourcesStore:
+				if _, ok := store.(*ociStagedSourcesStore); !ok {
+					t.Fatalf("NewStagedSourcesStore(%q) = %T, want *ociStagedSourcesStore", tc.bucket, store)
+				}
+			case *httpStagedSourcesStore:
+				if _, ok := store.(*httpStagedSourcesStore); !ok {
+					t.Fatalf("NewStagedSourcesStore(%q) = %T, want *httpStagedSourcesStore", tc.bucket, store)
+				}
+			}
+		})
+	}
+}
+
+func TestNewStagedSourcesStoreGCSBucketNormalization(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		bucket string
+		want   string
+	}{
+		{name: "bare bucket", bucket: "my-bucket", want: "my-bucket"},
+		{name: "gs scheme, bare bucket", bucket: "gs://my-bucket", want: "my-bucket"},
+		{name: "gs scheme with prefix", bucket: "gs://my-bucket/prefix", want: "my-bucket/prefix"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			store, err := NewStagedSourcesStore(tc.bucket)
+			if err != nil {
+				t.Fatalf("NewStagedSourcesStore(%q): unexpected error: %v", tc.bucket, err)
+			}
+			gcs, ok := store.(*gcsStagedSourcesStore)
+			if !ok {
+				t.Fatalf("NewStagedSourcesStore(%q) = %T, want *gcsStagedSourcesStore", tc.bucket, store)
+			}
+			if gcs.bucket != tc.want {
+				t.Fatalf("NewStagedSourcesStore(%q) bucket = %q, want %q", tc.bucket, gcs.bucket, tc.want)
+			}
+
+			const path = "stage/v1.2.3/kubernetes-src.tar.gz"
+			want := tc.want + "/" + path
+			if got := gcs.URL(path); got != want {
+				t.Fatalf("URL(%q) = %q, want %q", path, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sigstore/cosign/v2/pkg/cosign"
+	"github.com/sigstore/cosign/v2/pkg/cosign/bundle"
+	"github.com/sigstore/cosign/v2/pkg/cosign/fulcioroots"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"gopkg.in/yaml.v3"
+)
+
+// StagedSourcesTrustPolicy configures which signers are accepted when
+// verifying a staged SourcesTar, loaded from the file named by
+// --staged-sources-trust-policy.
+type StagedSourcesTrustPolicy struct {
+	// KeyPath, if set, verifies against this static public key instead
+	// of keyless/OIDC verification.
+	KeyPath string `yaml:"keyPath,omitempty"`
+	// OIDCIssuer is the required keyless-signing OIDC issuer, e.g.
+	// "https://token.actions.githubusercontent.com" or Prow's issuer.
+	OIDCIssuer string `yaml:"oidcIssuer,omitempty"`
+	// SubjectRegexp matches the signing identity's certificate subject,
+	// e.g. a Prow/GitHub Actions job identity.
+	SubjectRegexp string `yaml:"subjectRegexp,omitempty"`
+}
+
+// LoadStagedSourcesTrustPolicy reads a StagedSourcesTrustPolicy from path.
+func LoadStagedSourcesTrustPolicy(path string) (*StagedSourcesTrustPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading trust policy %s", path)
+	}
+
+	policy := &StagedSourcesTrustPolicy{}
+	if err := yaml.Unmarshal(data, policy); err != nil {
+		return nil, errors.Wrapf(err, "parsing trust policy %s", path)
+	}
+
+	return policy, nil
+}
+
+// sigSuffix and certSuffix name the co-located signature/certificate
+// objects verifyStagedSources looks for next to SourcesTar. sigSuffix
+// holds cosign's base64-encoded signature; certSuffix holds the keyless
+// signing certificate bundled with its Rekor inclusion proof, as written
+// by `cosign sign-blob --bundle`.
+const (
+	sigSuffix  = ".sig"
+	certSuffix = ".cert"
+)
+
+// verifyStagedSources verifies the SourcesTar downloaded to dst against
+// its co-located "<path>.sig" (and, for keyless signing, "<path>.cert"
+// certificate/Rekor bundle), fetched from store, failing closed if
+// verification does not succeed.
+func verifyStagedSources(ctx context.Context, store StagedSourcesStore, path, dst string, policy *StagedSourcesTrustPolicy) error {
+	sigTempDir, err := os.MkdirTemp("", "staged-sources-sig-")
+	if err != nil {
+		return errors.Wrap(err, "create signature temp dir")
+	}
+	defer os.RemoveAll(sigTempDir)
+
+	sigDst := sigTempDir + "/SourcesTar.sig"
+	if err := store.Fetch(path+sigSuffix, sigDst); err != nil {
+		return errors.Wrapf(err, "fetching signature for %s", store.URL(path))
+	}
+	sigB64, err := os.ReadFile(sigDst)
+	if err != nil {
+		return errors.Wrap(err, "reading fetched signature")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sigB64)))
+	if err != nil {
+		return errors.Wrap(err, "base64-decoding staged sources signature")
+	}
+
+	artifact, err := os.Open(dst)
+	if err != nil {
+		return errors.Wrap(err, "opening staged sources for verification")
+	}
+	defer artifact.Close()
+
+	if policy.KeyPath != "" {
+		return verifyWithStaticKey(policy.KeyPath, artifact, sig)
+	}
+
+	certDst := sigTempDir + "/SourcesTar.cert"
+	if err := store.Fetch(path+certSuffix, certDst); err != nil {
+		return errors.Wrapf(err, "fetching certificate bundle for %s", store.URL(path))
+	}
+
+	return verifyKeyless(ctx, artifact, sig, certDst, policy)
+}
+
+// verifyWithStaticKey verifies sig over the contents read from artifact
+// using the public key at keyPath, for distributors who sign with
+// COSIGN_KEY instead of keyless OIDC.
+func verifyWithStaticKey(keyPath string, artifact io.Reader, sig []byte) error {
+	verifier, err := signature.LoadPublicKeyVerifierFromPEMFile(keyPath, nil)
+	if err != nil {
+		return errors.Wrapf(err, "loading public key %s", keyPath)
+	}
+
+	if err := verifier.VerifySignature(bytes.NewReader(sig), artifact); err != nil {
+		return errors.Wrap(err, "verifying staged sources signature")
+	}
+
+	return nil
+}
+
+// verifyKeyless verifies sig over the contents read from artifact using
+// the Fulcio-issued signing certificate bundled at certPath, requiring:
+//   - the certificate chains to a trusted Fulcio root (not merely that it
+//     carries the expected issuer/subject strings — those are attacker
+//     controlled on a self-signed certificate);
+//   - its inclusion in Rekor is attested by a signed entry timestamp from
+//     a known transparency-log key; and
+//   - the signing identity matches policy's OIDC issuer and subject
+//     regexp.
+func verifyKeyless(ctx context.Context, artifact io.Reader, sig []byte, certPath string, policy *StagedSourcesTrustPolicy) error {
+	bundleData, err := os.ReadFile(certPath)
+	if err != nil {
+		return errors.Wrap(err, "reading certificate bundle")
+	}
+
+	var signed cosign.LocalSignedPayload
+	if err := json.Unmarshal(bundleData, &signed); err != nil {
+		return errors.Wrap(err, "parsing certificate bundle")
+	}
+	if signed.Cert == "" || signed.Bundle == nil {
+		return errors.New("certificate bundle is missing a signing certificate or Rekor entry")
+	}
+
+	cert, err := parseSigningCertificate(signed.Cert)
+	if err != nil {
+		return errors.Wrap(err, "parsing signing certificate")
+	}
+
+	roots, err := fulcioroots.Get()
+	if err != nil {
+		return errors.Wrap(err, "loading Fulcio root CAs")
+	}
+	intermediates, err := fulcioroots.GetIntermediates()
+	if err != nil {
+		return errors.Wrap(err, "loading Fulcio intermediate CAs")
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return errors.Wrap(err, "signing certificate does not chain to a trusted Fulcio root")
+	}
+
+	if err := verifyRekorEntry(ctx, signed.Bundle); err != nil {
+		return errors.Wrap(err, "verifying Rekor inclusion")
+	}
+
+	if policy.OIDCIssuer != "" && cosign.CertIssuer(cert) != policy.OIDCIssuer {
+		return errors.Errorf("unexpected signing OIDC issuer %q, want %q", cosign.CertIssuer(cert), policy.OIDCIssuer)
+	}
+
+	if policy.SubjectRegexp != "" {
+		re, err := regexp.Compile(policy.SubjectRegexp)
+		if err != nil {
+			return errors.Wrapf(err, "compiling subject regexp %q", policy.SubjectRegexp)
+		}
+		if !re.MatchString(cosign.CertSubject(cert)) {
+			return errors.Errorf("signing identity %q does not match trusted subject pattern %q", cosign.CertSubject(cert), policy.SubjectRegexp)
+		}
+	}
+
+	verifier, err := signature.LoadVerifier(cert.PublicKey, nil)
+	if err != nil {
+		return errors.Wrap(err, "loading verifier from certificate")
+	}
+
+	if err := verifier.VerifySignature(bytes.NewReader(sig), artifact); err != nil {
+		return errors.Wrap(err, "verifying staged sources signature")
+	}
+
+	return nil
+}
+
+// parseSigningCertificate decodes a cosign bundle's "cert" field, which is
+// a base64-wrapped PEM certificate.
+func parseSigningCertificate(encoded string) (*x509.Certificate, error) {
+	certPEM, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		// Older bundles stored the PEM certificate unwrapped; tolerate both.
+		certPEM = []byte(encoded)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("decoding signing certificate PEM")
+	}
+
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// verifyRekorEntry checks that rb's signed entry timestamp validates
+// against a known Rekor log public key, so a self-signed certificate with
+// no real transparency-log entry is rejected even if its issuer/subject
+// strings happen to match policy.
+func verifyRekorEntry(ctx context.Context, rb *bundle.RekorBundle) error {
+	pubs, err := cosign.GetRekorPubs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "fetching trusted Rekor public keys")
+	}
+
+	pub, ok := pubs[rb.Payload.LogID]
+	if !ok {
+		return errors.Errorf("unknown Rekor log ID %q", rb.Payload.LogID)
+	}
+
+	payload, err := json.Marshal(rb.Payload)
+	if err != nil {
+		return errors.Wrap(err, "marshaling Rekor payload")
+	}
+
+	return cosign.VerifySET(payload, rb.SignedEntryTimestamp, pub.PubKey)
+}
+
+// signStagedSources produces a detached, base64-encoded signature over
+// artifactPath's contents using the static key at keyPath, the staging-
+// side counterpart to verifyWithStaticKey. It is called by the stage
+// pipeline after SourcesTar is written, so that a release with
+// VerifyStagedSources enabled has something to verify against; keyless
+// OIDC signing is performed by invoking `cosign sign-blob` directly from
+// the staging job rather than from this library, since it requires an
+// interactive or CI-provided OIDC identity token.
+func signStagedSources(keyPath, artifactPath string) (sigBase64 []byte, err error) {
+	signer, err := signature.LoadSignerFromPEMFile(keyPath, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "loading signing key %s", keyPath)
+	}
+
+	artifact, err := os.Open(artifactPath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "opening %s", artifactPath)
+	}
+	defer artifact.Close()
+
+	sig, err := signer.SignMessage(artifact)
+	if err != nil {
+		return nil, errors.Wrap(err, "signing staged sources")
+	}
+
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(sig)))
+	base64.StdEncoding.Encode(encoded, sig)
+	return encoded, nil
+}
+
+// SignStagedSources signs artifactPath with the static key at keyPath and
+// writes the result to "<artifactPath>.sig", the file verifyStagedSources
+// fetches back via store.Fetch(path+sigSuffix, ...). PrepareWorkspaceStage
+// calls this once SourcesTar has been written and is ready for upload, so
+// that a release with VerifyStagedSources enabled has a signature to
+// verify against.
+func SignStagedSources(keyPath, artifactPath string) error {
+	sig, err := signStagedSources(keyPath, artifactPath)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(artifactPath+sigSuffix, sig, 0o644); err != nil {
+		return errors.Wrapf(err, "writing %s", artifactPath+sigSuffix)
+	}
+
+	return nil
+}
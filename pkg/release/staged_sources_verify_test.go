@@ -0,0 +1,147 @@
+/*
+Copyright 2020 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package release
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func selfSignedTestCert(t *testing.T) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating test key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(1<<31-1, 0),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating test certificate: %v", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing test certificate: %v", err)
+	}
+
+	return cert
+}
+
+func TestParseSigningCertificate(t *testing.T) {
+	cert := selfSignedTestCert(t)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+
+	t.Run("base64-wrapped PEM", func(t *testing.T) {
+		encoded := base64.StdEncoding.EncodeToString(certPEM)
+		got, err := parseSigningCertificate(encoded)
+		if err != nil {
+			t.Fatalf("parseSigningCertificate: unexpected error: %v", err)
+		}
+		if got.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			t.Fatalf("parseSigningCertificate: got serial %v, want %v", got.SerialNumber, cert.SerialNumber)
+		}
+	})
+
+	t.Run("raw PEM", func(t *testing.T) {
+		got, err := parseSigningCertificate(string(certPEM))
+		if err != nil {
+			t.Fatalf("parseSigningCertificate: unexpected error: %v", err)
+		}
+		if got.SerialNumber.Cmp(cert.SerialNumber) != 0 {
+			t.Fatalf("parseSigningCertificate: got serial %v, want %v", got.SerialNumber, cert.SerialNumber)
+		}
+	})
+
+	t.Run("garbage input", func(t *testing.T) {
+		if _, err := parseSigningCertificate("not a certificate"); err == nil {
+			t.Fatal("parseSigningCertificate: expected error for garbage input, got none")
+		}
+	})
+}
+
+func TestSignStagedSourcesRoundTrip(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating test key: %v", err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshaling test key: %v", err)
+	}
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "cosign.key")
+	if err := os.WriteFile(keyPath, keyPEM, 0o600); err != nil {
+		t.Fatalf("writing test key: %v", err)
+	}
+
+	artifactPath := filepath.Join(dir, "kubernetes-src.tar.gz")
+	if err := os.WriteFile(artifactPath, []byte("fake staged sources"), 0o644); err != nil {
+		t.Fatalf("writing test artifact: %v", err)
+	}
+
+	if err := SignStagedSources(keyPath, artifactPath); err != nil {
+		t.Fatalf("SignStagedSources: unexpected error: %v", err)
+	}
+
+	sigB64, err := os.ReadFile(artifactPath + sigSuffix)
+	if err != nil {
+		t.Fatalf("reading written signature: %v", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(string(sigB64))
+	if err != nil {
+		t.Fatalf("base64-decoding written signature: %v", err)
+	}
+
+	pubPEM, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("marshaling test public key: %v", err)
+	}
+	pubKeyPath := filepath.Join(dir, "cosign.pub")
+	if err := os.WriteFile(pubKeyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubPEM}), 0o600); err != nil {
+		t.Fatalf("writing test public key: %v", err)
+	}
+
+	artifact, err := os.Open(artifactPath)
+	if err != nil {
+		t.Fatalf("opening signed artifact: %v", err)
+	}
+	defer artifact.Close()
+
+	if err := verifyWithStaticKey(pubKeyPath, artifact, sig); err != nil {
+		t.Fatalf("verifyWithStaticKey: signature produced by SignStagedSources did not verify: %v", err)
+	}
+}
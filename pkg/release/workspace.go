@@ -17,6 +17,7 @@ limitations under the License.
 package release
 
 import (
+	"context"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -26,22 +27,67 @@ import (
 	"github.com/sirupsen/logrus"
 
 	"k8s.io/release/pkg/git"
-	"k8s.io/release/pkg/github"
 	"k8s.io/release/pkg/license"
-	"k8s.io/release/pkg/object"
 	"k8s.io/release/pkg/spdx"
 	"sigs.k8s.io/release-utils/tar"
 )
 
-// PrepareWorkspaceStage sets up the workspace by cloning a new copy of k/k.
-func PrepareWorkspaceStage(directory string) error {
+// workspaceHookEnvKey names the env var read for the ordered list of
+// workspace hooks to run when the caller does not pass hookNames
+// explicitly, as a comma-separated list of names registered with
+// RegisterWorkspaceHook.
+const workspaceHookEnvKey = "RELEASE_WORKSPACE_HOOKS"
+
+// resolveWorkspaceHookNames returns hookNames if non-empty, falling back to
+// the comma-separated list in the workspaceHookEnvKey env var.
+func resolveWorkspaceHookNames(hookNames []string) []string {
+	if len(hookNames) > 0 {
+		return hookNames
+	}
+	env, ok := os.LookupEnv(workspaceHookEnvKey)
+	if !ok || env == "" {
+		return nil
+	}
+	return strings.Split(env, ",")
+}
+
+// PrepareWorkspaceStageOptions configures the optional license-policy
+// enforcement gate run by PrepareWorkspaceStage.
+type PrepareWorkspaceStageOptions struct {
+	// LicensePolicy, if non-nil, turns the prewarmed SPDX catalog into an
+	// enforcement gate: every vendor/third_party license file is
+	// classified and the stage fails if any file is denied, or is
+	// unclassified because its match falls below policy's confidence
+	// threshold, unless waived via .license-waivers.yaml.
+	LicensePolicy *license.Policy
+	// SourcesTarSigningKey, if set, signs SourcesTarPath with this static
+	// key once the workspace hooks have run, writing "<SourcesTarPath>.sig"
+	// so that release's --verify-staged-sources has a signature to verify
+	// against. SourcesTarPath must also be set.
+	SourcesTarSigningKey string
+	// SourcesTarPath is the staged sources tarball to sign, produced by
+	// the stage pipeline before PrepareWorkspaceStage returns. Required
+	// when SourcesTarSigningKey is set; ignored otherwise.
+	SourcesTarPath string
+}
+
+// PrepareWorkspaceStage sets up the workspace by cloning a new copy of k/k,
+// then runs each named hook in hookNames (see RegisterWorkspaceHook) in
+// order, falling back to the RELEASE_WORKSPACE_HOOKS env var when
+// hookNames is empty. It returns a context seeded with the prewarmed SPDX
+// license catalog via license.SetContextLicenseScanner, so that SBOM/
+// license consumers further down the stage pipeline can reuse it instead
+// of reconstructing (and re-downloading) their own.
+func PrepareWorkspaceStage(directory string, options PrepareWorkspaceStageOptions, hookNames ...string) (context.Context, error) {
+	ctx := context.Background()
+
 	logrus.Infof("Preparing workspace for staging in %s", directory)
 	logrus.Infof("Cloning repository to %s", directory)
 	_, err := git.CloneOrOpenGitHubRepo(
 		directory, git.DefaultGithubOrg, git.DefaultGithubRepo, false,
 	)
 	if err != nil {
-		return errors.Wrap(err, "clone k/k repository")
+		return ctx, errors.Wrap(err, "clone k/k repository")
 	}
 
 	// Prewarm the SPDX licenses cache. As it is one of the main
@@ -53,19 +99,66 @@ func PrepareWorkspaceStage(directory string) error {
 	doptions.CacheDir = s.Options().LicenseCacheDir
 	downloader, err := license.NewDownloaderWithOptions(doptions)
 	if err != nil {
-		return errors.Wrap(err, "creating license downloader")
+		return ctx, errors.Wrap(err, "creating license downloader")
 	}
 	// Fetch the SPDX licenses
-	if _, err := downloader.GetLicenses(); err != nil {
-		return errors.Wrap(err, "retrieving SPDX licenses")
+	licenses, err := downloader.GetLicenses()
+	if err != nil {
+		return ctx, errors.Wrap(err, "retrieving SPDX licenses")
+	}
+	reader, err := license.NewReaderWithLicenses(licenses)
+	if err != nil {
+		return ctx, errors.Wrap(err, "creating license classifier")
 	}
 
-	return nil
+	catalog := &license.Catalog{
+		Downloader: downloader,
+		Licenses:   licenses,
+		Reader:     reader,
+	}
+	ctx = license.SetContextLicenseScanner(ctx, catalog)
+
+	if options.LicensePolicy != nil {
+		logrus.Info("Enforcing SPDX license policy on vendored dependencies")
+		if err := enforceLicensePolicy(ctx, directory, options.LicensePolicy); err != nil {
+			return ctx, errors.Wrap(err, "enforcing license policy")
+		}
+	}
+
+	if err := runWorkspaceHooks(ctx, resolveWorkspaceHookNames(hookNames), directory, ""); err != nil {
+		return ctx, err
+	}
+
+	if options.SourcesTarSigningKey != "" {
+		logrus.Infof("Signing staged sources at %s", options.SourcesTarPath)
+		if err := SignStagedSources(options.SourcesTarSigningKey, options.SourcesTarPath); err != nil {
+			return ctx, errors.Wrap(err, "signing staged sources")
+		}
+	}
+
+	return ctx, nil
 }
 
-// PrepareWorkspaceRelease sets up the workspace by downloading and extracting
-// the staged sources on the provided bucket.
-func PrepareWorkspaceRelease(directory, buildVersion, bucket string) error {
+// PrepareWorkspaceReleaseOptions configures the optional, security-relevant
+// behavior of PrepareWorkspaceRelease: verifying the staged SourcesTar
+// against a trust policy before it is extracted. It corresponds to the
+// release CLI's --verify-staged-sources and --staged-sources-trust-policy
+// flags.
+type PrepareWorkspaceReleaseOptions struct {
+	// VerifyStagedSources, when true, requires a valid cosign signature
+	// (and optional Rekor bundle) for SourcesTar before extraction,
+	// failing closed if verification does not succeed.
+	VerifyStagedSources bool
+	// StagedSourcesTrustPolicy is the path to the trust policy file
+	// consulted when VerifyStagedSources is true.
+	StagedSourcesTrustPolicy string
+}
+
+// PrepareWorkspaceRelease sets up the workspace by downloading and
+// extracting the staged sources on the provided bucket, then runs each
+// named hook in hookNames (see RegisterWorkspaceHook) in order, falling
+// back to the RELEASE_WORKSPACE_HOOKS env var when hookNames is empty.
+func PrepareWorkspaceRelease(directory, buildVersion, bucket string, options PrepareWorkspaceReleaseOptions, hookNames ...string) error {
 	logrus.Infof("Preparing workspace for release in %s", directory)
 	logrus.Infof("Searching for staged %s on %s", SourcesTar, bucket)
 	tempDir, err := os.MkdirTemp("", "staged-")
@@ -74,14 +167,30 @@ func PrepareWorkspaceRelease(directory, buildVersion, bucket string) error {
 	}
 	defer os.RemoveAll(tempDir)
 
-	// On `release`, we lookup the staged sources and use them directly
-	src := filepath.Join(bucket, StagePath, buildVersion, SourcesTar)
+	// On `release`, we lookup the staged sources and use them directly.
+	// bucket may point at GCS (the default), S3, an OCI registry, or a
+	// plain HTTP(S) endpoint, selected by its URL scheme.
+	store, err := NewStagedSourcesStore(bucket)
+	if err != nil {
+		return errors.Wrap(err, "resolving staged sources store")
+	}
+
+	src := filepath.Join(StagePath, buildVersion, SourcesTar)
 	dst := filepath.Join(tempDir, SourcesTar)
 
-	gcs := object.NewGCS()
-	gcs.WithAllowMissing(false)
-	if err := gcs.CopyToLocal(src, dst); err != nil {
-		return errors.Wrap(err, "copying staged sources from GCS")
+	if err := store.Fetch(src, dst); err != nil {
+		return errors.Wrapf(err, "copying staged sources from %s", store.URL(src))
+	}
+
+	if options.VerifyStagedSources {
+		logrus.Infof("Verifying staged sources against trust policy %s", options.StagedSourcesTrustPolicy)
+		policy, err := LoadStagedSourcesTrustPolicy(options.StagedSourcesTrustPolicy)
+		if err != nil {
+			return errors.Wrap(err, "loading staged sources trust policy")
+		}
+		if err := verifyStagedSources(context.Background(), store, src, dst, policy); err != nil {
+			return errors.Wrap(err, "staged sources failed signature verification")
+		}
 	}
 
 	logrus.Info("Got staged sources, extracting archive")
@@ -91,25 +200,39 @@ func PrepareWorkspaceRelease(directory, buildVersion, bucket string) error {
 		return errors.Wrapf(err, "extracting %s", dst)
 	}
 
-	// Reset the github token in the staged k/k clone
-	token, ok := os.LookupEnv(github.TokenEnvKey)
-	if !ok {
-		return errors.Errorf("%s env variable is not set", github.TokenEnvKey)
-	}
-
 	repo, err := git.OpenRepo(directory)
 	if err != nil {
 		return errors.Wrap(err, "opening staged clone of k/k")
 	}
 
+	// Detect which provider the staged clone's remote already points at
+	// (GitHub by default, but GitLab/Bitbucket/self-hosted mirrors are
+	// supported too) and reset the remote using that provider's token,
+	// rather than assuming GitHub.
+	provider, err := git.DetectRemoteProvider(repo, git.DefaultRemote)
+	if err != nil {
+		return errors.Wrap(err, "detecting git remote provider")
+	}
+
+	token, err := git.TokenFromEnv(provider)
+	if err != nil {
+		return err
+	}
+
 	if err := repo.SetURL(git.DefaultRemote, (&url.URL{
 		Scheme: "https",
-		User:   url.UserPassword("git", token),
-		Host:   "github.com",
-		Path:   filepath.Join(git.DefaultGithubOrg, git.DefaultGithubRepo),
+		User:   url.UserPassword(provider.BasicAuthUser(), token),
+		Host:   provider.Host(),
+		Path:   provider.RepoPath(git.DefaultGithubOrg, git.DefaultGithubRepo),
 	}).String()); err != nil {
 		return errors.Wrap(err, "changing git remote of repository")
 	}
 
+	if err := runWorkspaceHooks(
+		context.Background(), resolveWorkspaceHookNames(hookNames), directory, buildVersion,
+	); err != nil {
+		return err
+	}
+
 	return nil
 }